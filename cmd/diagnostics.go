@@ -0,0 +1,54 @@
+// Package cmd provides command-line interfaces for luactl.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/fatih/color"
+
+	"github.com/lablabs/luactl/internal/sync"
+)
+
+// renderDiagnostics prints diags to stderr with file:line:col context and a
+// colored severity summary. When the logger is configured at debug level,
+// the offending source line is printed beneath each diagnostic that has one.
+func renderDiagnostics(ctx context.Context, logger *slog.Logger, diags sync.Diagnostics) {
+	if len(diags) == 0 {
+		return
+	}
+
+	debug := logger.Enabled(ctx, slog.LevelDebug)
+	errorLabel := color.New(color.FgRed, color.Bold).SprintFunc()
+	warningLabel := color.New(color.FgYellow, color.Bold).SprintFunc()
+
+	for _, diag := range diags {
+		label := warningLabel("warning")
+		if diag.Severity == sync.SeverityError {
+			label = errorLabel("error")
+		}
+
+		location := diag.Module
+		if diag.Subject != nil {
+			location = fmt.Sprintf("%s: %s:%d:%d", diag.Module, diag.FilePath, diag.Subject.Start.Line, diag.Subject.Start.Column)
+		} else if diag.FilePath != "" {
+			location = fmt.Sprintf("%s: %s", diag.Module, diag.FilePath)
+		}
+
+		fmt.Fprintf(os.Stderr, "%s: %s: %s\n", label, location, diag.Summary)
+		if diag.Detail != "" {
+			fmt.Fprintf(os.Stderr, "  %s\n", diag.Detail)
+		}
+		if debug && diag.Snippet != "" {
+			fmt.Fprintf(os.Stderr, "  | %s\n", diag.Snippet)
+		}
+	}
+
+	summaryColor := color.New(color.FgYellow)
+	if diags.HasErrors() {
+		summaryColor = color.New(color.FgRed)
+	}
+	summaryColor.Fprintf(os.Stderr, "%d error(s), %d warning(s)\n", diags.ErrorCount(), diags.WarningCount())
+}
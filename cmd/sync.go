@@ -2,6 +2,10 @@
 package cmd
 
 import (
+	"os"
+	"runtime"
+
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
 	"github.com/lablabs/luactl/internal/sync"
@@ -10,6 +14,9 @@ import (
 // NewSyncCmd creates and returns the sync command.
 func NewSyncCmd() *cobra.Command {
 	var workDir, targetDir, modulesDir string
+	var check, diff bool
+	var concurrency int
+	var containerImage string
 
 	// syncCmd represents the sync command.
 	syncCmd := &cobra.Command{
@@ -17,20 +24,33 @@ func NewSyncCmd() *cobra.Command {
 		Short: "Syncs variables from addon submodules to the root module",
 		Long: `Reads variables.tf files from nested addon modules within the
 .terraform/modules directory and generates corresponding variables-<addon-name>.tf
-files in the current directory.`,
+files in the current directory.
+
+With --check, no files are written; sync instead reports whether the
+generated files are already up to date and exits non-zero if any addon
+has drifted, mirroring "terraform fmt -check". Combine with --diff to
+print a unified diff of the drift.`,
 		RunE: func(_ *cobra.Command, _ []string) error {
 			ctx, cancel := CreateContextWithTimeout()
 			defer cancel()
 
 			logger := GetLogger()
 
-			processor, err := sync.NewVariableProcessor(logger, workDir, targetDir, modulesDir)
+			if containerImage != "" {
+				if err := materializeModules(ctx, logger, containerImage, workDir); err != nil {
+					logger.Error("Failed to materialize .terraform/modules", "error", err)
+					return err
+				}
+			}
+
+			processor, err := sync.NewVariableProcessor(logger, afero.NewOsFs(), workDir, targetDir, modulesDir, check || diff, diff, concurrency)
 			if err != nil {
 				logger.Error("Failed to initialize variable processor", "error", err)
 				return err
 			}
 
-			syncErr := processor.ProcessModules(ctx)
+			diags, syncErr := processor.ProcessModules(ctx)
+			renderDiagnostics(ctx, logger, diags)
 			if syncErr != nil {
 				logger.Error("Variable synchronization failed", "error", syncErr)
 				return syncErr
@@ -47,6 +67,18 @@ files in the current directory.`,
 		"Directory to output synced files to")
 	syncCmd.Flags().StringVarP(&modulesDir, "modules-dir", "", ".terraform/modules",
 		"Directory containing Terraform modules, will be prepended by work-dir")
+	syncCmd.Flags().BoolVarP(&check, "check", "", false,
+		"Check that generated files are up to date without writing them, exiting non-zero if any addon has drifted")
+	syncCmd.Flags().BoolVarP(&diff, "diff", "", false,
+		"Print a unified diff of drifted files, implies --check")
+	syncCmd.Flags().IntVarP(&concurrency, "concurrency", "", runtime.NumCPU(),
+		"Maximum number of addon modules to process in parallel")
+	syncCmd.Flags().StringVarP(&containerImage, "container-image", "", os.Getenv("LUACTL_CONTAINER_IMAGE"),
+		"Run `terraform init -backend=false` in this container image (via Docker or Podman) to materialize "+
+			".terraform/modules before syncing, so Terraform need not be installed locally (env LUACTL_CONTAINER_IMAGE)")
+	syncCmd.Flags().Lookup("container-image").NoOptDefVal = defaultContainerImage
+
+	syncCmd.AddCommand(NewSyncWatchCmd())
 
 	return syncCmd
 }
@@ -0,0 +1,30 @@
+// Package cmd provides command-line interfaces for luactl.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	ctrruntime "github.com/lablabs/luactl/internal/runtime"
+)
+
+// defaultContainerImage is used when --container-image is passed with no
+// value, so users don't need to know the exact Terraform image tag to opt in.
+const defaultContainerImage = "hashicorp/terraform:latest"
+
+// materializeModules runs "terraform init -backend=false" inside image to
+// populate workDir/.terraform/modules/modules.json, so sync can run against
+// the resulting tree without a local Terraform install.
+func materializeModules(ctx context.Context, logger *slog.Logger, image, workDir string) error {
+	rt, err := ctrruntime.New(logger)
+	if err != nil {
+		return fmt.Errorf("failed to detect container runtime: %w", err)
+	}
+
+	if err := rt.Run(ctx, image, workDir, []string{"init", "-backend=false"}); err != nil {
+		return fmt.Errorf("failed to materialize .terraform/modules: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+// Package cmd provides command-line interfaces for luactl.
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/bep/debounce"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/lablabs/luactl/internal/sync"
+)
+
+// NewSyncWatchCmd creates and returns the "sync watch" subcommand.
+func NewSyncWatchCmd() *cobra.Command {
+	var workDir, targetDir, modulesDir string
+	var check, diff bool
+	var concurrency int
+	var debounceInterval time.Duration
+
+	watchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Watches addon modules and re-syncs variables on change",
+		Long: `Watches .terraform/modules for changes to variables.tf files in
+addon* module directories and re-runs the sync whenever one changes,
+coalescing the burst of filesystem events produced by "terraform init"
+or "terraform get" behind a debounce window. Combine with --check to
+keep the process running while surfacing drift as a status line instead
+of exiting non-zero.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			logger := GetLogger()
+
+			processor, err := sync.NewVariableProcessor(logger, afero.NewOsFs(), workDir, targetDir, modulesDir,
+				check || diff, diff, concurrency)
+			if err != nil {
+				logger.Error("Failed to initialize variable processor", "error", err)
+				return err
+			}
+
+			run := func() {
+				ctx, cancel := CreateContextWithTimeout()
+				defer cancel()
+
+				diags, syncErr := processor.ProcessModules(ctx)
+				renderDiagnostics(ctx, logger, diags)
+				if syncErr != nil {
+					logger.Warn("Sync status", "status", "out of date", "error", syncErr)
+					return
+				}
+				logger.Info("Sync status", "status", "up to date")
+			}
+
+			run()
+
+			absModulesDir := filepath.Join(workDir, modulesDir)
+
+			watcher, err := fsnotify.NewWatcher()
+			if err != nil {
+				return fmt.Errorf("failed to create filesystem watcher: %w", err)
+			}
+			defer watcher.Close()
+
+			ctx := cmd.Context()
+
+			watchedDirs, err := watchAddonModules(ctx, watcher, processor, absModulesDir)
+			if err != nil {
+				logger.Error("Failed to watch modules directory", "path", absModulesDir, "error", err)
+				return err
+			}
+
+			debounced := debounce.New(debounceInterval)
+
+			logger.Info("Watching for addon variable changes", "modulesDir", absModulesDir, "debounce", debounceInterval)
+
+			for {
+				select {
+				case event, ok := <-watcher.Events:
+					if !ok {
+						return nil
+					}
+					if !isWatchedVariablesFile(event.Name, watchedDirs) {
+						continue
+					}
+					logger.Debug("Detected change", "path", event.Name, "op", event.Op.String())
+					debounced(run)
+				case watchErr, ok := <-watcher.Errors:
+					if !ok {
+						return nil
+					}
+					logger.Error("Watcher error", "error", watchErr)
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		},
+	}
+
+	watchCmd.Flags().StringVarP(&workDir, "work-dir", "", ".",
+		"Directory to work in")
+	watchCmd.Flags().StringVarP(&targetDir, "target-dir", "", ".",
+		"Directory to output synced files to")
+	watchCmd.Flags().StringVarP(&modulesDir, "modules-dir", "", ".terraform/modules",
+		"Directory containing Terraform modules, will be prepended by work-dir")
+	watchCmd.Flags().BoolVarP(&check, "check", "", false,
+		"Check that generated files are up to date without writing them, surfacing drift via a status line")
+	watchCmd.Flags().BoolVarP(&diff, "diff", "", false,
+		"Print a unified diff of drifted files, implies --check")
+	watchCmd.Flags().IntVarP(&concurrency, "concurrency", "", runtime.NumCPU(),
+		"Maximum number of addon modules to process in parallel")
+	watchCmd.Flags().DurationVarP(&debounceInterval, "debounce", "", 500*time.Millisecond,
+		"Time to wait for a burst of filesystem events to settle before re-syncing")
+
+	return watchCmd
+}
+
+// watchAddonModules adds an fsnotify watch for modulesDir itself and for the
+// SourceDir of every addon module processor.DiscoverAddonModules
+// currently finds, via modules.json when present and the directory-scan
+// fallback otherwise. It returns the set of directories watched on behalf of
+// an addon module, so isWatchedVariablesFile can recognize a relevant
+// variables.tf without assuming anything about its on-disk directory name.
+// Discovery runs once, so addon modules added by a later "terraform init" are
+// not watched until the command is restarted.
+func watchAddonModules(ctx context.Context, watcher *fsnotify.Watcher, processor *sync.VariableProcessor, modulesDir string) (map[string]bool, error) {
+	if err := watcher.Add(modulesDir); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to watch %q: %w", modulesDir, err)
+	}
+
+	modules, _, err := processor.DiscoverAddonModules(ctx)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to discover addon modules: %w", err)
+	}
+
+	watchedDirs := make(map[string]bool, len(modules))
+	for _, module := range modules {
+		if err := watcher.Add(module.SourceDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to watch %q: %w", module.SourceDir, err)
+		}
+		watchedDirs[module.SourceDir] = true
+	}
+
+	return watchedDirs, nil
+}
+
+// isWatchedVariablesFile reports whether path is a variables.tf file directly
+// inside one of watchedDirs.
+func isWatchedVariablesFile(path string, watchedDirs map[string]bool) bool {
+	return filepath.Base(path) == "variables.tf" && watchedDirs[filepath.Dir(path)]
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lablabs/luactl/internal/sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsWatchedVariablesFile(t *testing.T) {
+	watchedDirs := map[string]bool{
+		"/work/modules/addon": true,
+	}
+
+	assert.True(t, isWatchedVariablesFile("/work/modules/addon/variables.tf", watchedDirs))
+	assert.False(t, isWatchedVariablesFile("/work/modules/addon/main.tf", watchedDirs),
+		"only variables.tf should be watched")
+	assert.False(t, isWatchedVariablesFile("/work/modules/other/variables.tf", watchedDirs),
+		"a directory that was never watched must not match")
+}
+
+// TestWatchAddonModules_WatchesEveryDiscoveredModule exercises watchAddonModules
+// against a real fsnotify.Watcher (cheap and dependency-free to construct,
+// unlike the container runtime), asserting it returns the SourceDir of every
+// addon module discovered rather than pattern-matching directory names.
+// fsnotify watches real OS paths, so this uses an on-disk fixture rather
+// than the MemMapFs the rest of the suite prefers.
+func TestWatchAddonModules_WatchesEveryDiscoveredModule(t *testing.T) {
+	workDir := t.TempDir()
+	sourceDir := filepath.Join(workDir, "modules", "addon", "modules", "addon")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "variables.tf"), []byte(`variable "name" {
+  type    = string
+  default = ""
+}
+`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "addon.tf"), []byte(`module "addon" {
+  source = "./modules/addon"
+}
+`), 0600))
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	processor, err := sync.NewVariableProcessor(logger, afero.NewOsFs(), workDir, workDir, "modules", false, false, 1)
+	require.NoError(t, err)
+
+	watcher, err := fsnotify.NewWatcher()
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	watchedDirs, err := watchAddonModules(t.Context(), watcher, processor, filepath.Join(workDir, "modules"))
+	require.NoError(t, err)
+	assert.True(t, watchedDirs[sourceDir], "the discovered addon module's source dir must be watched")
+}
+
+// TestWatchAddonModules_MissingModulesDirIsNotAnError exercises the
+// sync-watch startup path before "terraform init" has ever run, where
+// modulesDir doesn't exist yet.
+func TestWatchAddonModules_MissingModulesDirIsNotAnError(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	processor, err := sync.NewVariableProcessor(logger, fs, "/work", "/work", "modules", false, false, 1)
+	require.NoError(t, err)
+
+	watcher, err := fsnotify.NewWatcher()
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	watchedDirs, err := watchAddonModules(t.Context(), watcher, processor, "/work/modules")
+	require.NoError(t, err)
+	assert.Nil(t, watchedDirs)
+}
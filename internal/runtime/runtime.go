@@ -0,0 +1,128 @@
+// Package runtime selects and drives a container runtime (Docker or Podman)
+// used to materialize Terraform's .terraform/modules directory without
+// requiring a local Terraform install.
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Engine identifies a container runtime binary.
+type Engine string
+
+// Supported container engines, tried in this order.
+const (
+	EngineDocker Engine = "docker"
+	EnginePodman Engine = "podman"
+)
+
+// Runtime runs commands inside a container using a detected container engine.
+type Runtime struct {
+	engine Engine
+	logger *slog.Logger
+}
+
+// New detects an available container engine, preferring Docker and falling
+// back to Podman, and returns a Runtime that drives it. It returns an error
+// if neither binary is found on PATH.
+func New(logger *slog.Logger) (*Runtime, error) {
+	for _, engine := range []Engine{EngineDocker, EnginePodman} {
+		if _, err := exec.LookPath(string(engine)); err == nil {
+			logger.Debug("Detected container engine", "engine", engine)
+			return &Runtime{engine: engine, logger: logger}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no container runtime found on PATH: tried %s, %s", EngineDocker, EnginePodman)
+}
+
+// Run mounts hostDir read-write at /workspace inside image and executes
+// command there, streaming the container's stdout/stderr through the
+// Runtime's logger.
+func (r *Runtime) Run(ctx context.Context, image, hostDir string, command []string) error {
+	absHostDir, err := filepath.Abs(hostDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve absolute path for %q: %w", hostDir, err)
+	}
+
+	args := runArgs(image, absHostDir, command)
+
+	r.logger.InfoContext(ctx, "Running container", "engine", r.engine, "image", image, "command", command)
+
+	stdout := &logWriter{ctx: ctx, logger: r.logger, level: slog.LevelInfo}
+	stderr := &logWriter{ctx: ctx, logger: r.logger, level: slog.LevelWarn}
+
+	cmd := exec.CommandContext(ctx, string(r.engine), args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	runErr := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+
+	if runErr != nil {
+		return fmt.Errorf("failed to run %s %s: %w", r.engine, strings.Join(command, " "), runErr)
+	}
+
+	return nil
+}
+
+// runArgs builds the container engine CLI arguments for Run. hostDir must
+// already be absolute: a bare relative path (especially ".") is ambiguous
+// between a bind mount and a named-volume reference to both Docker and
+// Podman's "-v" flag.
+func runArgs(image, hostDir string, command []string) []string {
+	return append([]string{
+		"run", "--rm",
+		"-v", fmt.Sprintf("%s:/workspace", hostDir),
+		"-w", "/workspace",
+		image,
+	}, command...)
+}
+
+// logWriter adapts an io.Writer onto a slog.Logger, splitting input on
+// newlines so each line of container output becomes its own log record.
+// exec.Cmd copies from the container's stdout/stderr pipe in arbitrary
+// chunks, so a line may straddle two Write calls; logWriter buffers any
+// trailing partial line and prepends it to the next Write.
+type logWriter struct {
+	ctx    context.Context
+	logger *slog.Logger
+	level  slog.Level
+	buf    strings.Builder
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+
+	data := w.buf.String()
+	lines := strings.Split(data, "\n")
+
+	// The last element is either "" (data ended on a newline) or a partial
+	// line to carry over to the next Write.
+	w.buf.Reset()
+	w.buf.WriteString(lines[len(lines)-1])
+
+	for _, line := range lines[:len(lines)-1] {
+		if line == "" {
+			continue
+		}
+		w.logger.Log(w.ctx, w.level, line, "source", "container")
+	}
+
+	return len(p), nil
+}
+
+// Flush logs any buffered partial line that never received a trailing
+// newline. Callers must invoke this after the underlying command exits.
+func (w *logWriter) Flush() {
+	if line := w.buf.String(); line != "" {
+		w.logger.Log(w.ctx, w.level, line, "source", "container")
+	}
+	w.buf.Reset()
+}
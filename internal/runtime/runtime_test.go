@@ -0,0 +1,78 @@
+package runtime
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingHandler captures the message of every log record it receives, so
+// tests can assert on what logWriter actually logged.
+type recordingHandler struct {
+	messages *[]string
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	*h.messages = append(*h.messages, r.Message)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs(_ []slog.Attr) slog.Handler { return h }
+func (h *recordingHandler) WithGroup(_ string) slog.Handler      { return h }
+
+// TestRunArgs_MountsAbsoluteHostDir guards against a regression to the
+// "-v hostDir:/workspace" bug: Docker and Podman can treat a bare relative
+// path (especially ".", --work-dir's default) as a named-volume reference
+// rather than a bind mount, so Run must always pass runArgs an absolute
+// hostDir.
+func TestRunArgs_MountsAbsoluteHostDir(t *testing.T) {
+	args := runArgs("hashicorp/terraform:latest", "/abs/work/dir", []string{"init", "-backend=false"})
+
+	assert.Equal(t, []string{
+		"run", "--rm",
+		"-v", "/abs/work/dir:/workspace",
+		"-w", "/workspace",
+		"hashicorp/terraform:latest",
+		"init", "-backend=false",
+	}, args)
+}
+
+// TestLogWriter_BuffersLineAcrossWrites guards against a regression where a
+// single line split across two Write calls (as exec.Cmd does when copying
+// from a pipe in arbitrary chunks) was logged as two broken fragments
+// instead of being reassembled into one record.
+func TestLogWriter_BuffersLineAcrossWrites(t *testing.T) {
+	var messages []string
+	logger := slog.New(&recordingHandler{messages: &messages})
+
+	w := &logWriter{ctx: t.Context(), logger: logger, level: slog.LevelInfo}
+
+	_, err := w.Write([]byte("Terraform has been success"))
+	assert.NoError(t, err)
+	assert.Empty(t, messages, "a partial line must not be logged until it is completed")
+
+	_, err = w.Write([]byte("fully initialized!\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Terraform has been successfully initialized!"}, messages)
+}
+
+// TestLogWriter_FlushEmitsTrailingPartialLine guards against losing the
+// final line of output when the container process exits without writing a
+// trailing newline.
+func TestLogWriter_FlushEmitsTrailingPartialLine(t *testing.T) {
+	var messages []string
+	logger := slog.New(&recordingHandler{messages: &messages})
+
+	w := &logWriter{ctx: t.Context(), logger: logger, level: slog.LevelInfo}
+
+	_, err := w.Write([]byte("partial line with no newline"))
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+
+	w.Flush()
+	assert.Equal(t, []string{"partial line with no newline"}, messages)
+}
@@ -0,0 +1,154 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Severity indicates how serious a Diagnostic is.
+type Severity int
+
+// Severity levels, ordered from least to most serious.
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+// String implements fmt.Stringer.
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic describes a single warning or error encountered while syncing an
+// addon module, modeled on hcl.Diagnostic, with enough context (module, file,
+// and optionally a source range) to locate it in a large addon tree.
+type Diagnostic struct {
+	Severity Severity
+	Module   string
+	FilePath string
+	Summary  string
+	Detail   string
+	// Subject is the source range the diagnostic refers to, populated for
+	// diagnostics derived from hclwrite.ParseConfig failures. It is nil for
+	// diagnostics raised outside of HCL parsing, such as I/O errors.
+	Subject *hcl.Range
+	// Snippet is the source line the Subject range points at, if known.
+	Snippet string
+}
+
+// String renders the diagnostic as "severity: module: file:line:col: summary".
+func (d *Diagnostic) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s: %s: ", d.Severity, d.Module)
+	switch {
+	case d.Subject != nil:
+		fmt.Fprintf(&b, "%s:%d:%d: ", d.FilePath, d.Subject.Start.Line, d.Subject.Start.Column)
+	case d.FilePath != "":
+		fmt.Fprintf(&b, "%s: ", d.FilePath)
+	}
+	b.WriteString(d.Summary)
+
+	if d.Detail != "" {
+		fmt.Fprintf(&b, "\n  %s", d.Detail)
+	}
+
+	return b.String()
+}
+
+// Diagnostics is an accumulated list of warnings and errors encountered while
+// syncing addon modules, modeled on hcl.Diagnostics and Terraform's tfdiags.
+type Diagnostics []*Diagnostic
+
+// Append adds a diagnostic to the list.
+func (ds *Diagnostics) Append(diag *Diagnostic) {
+	*ds = append(*ds, diag)
+}
+
+// AppendError appends a plain error-severity diagnostic with no source
+// position, for failures (such as I/O errors) that occur outside of HCL
+// parsing.
+func (ds *Diagnostics) AppendError(module, filePath string, err error) {
+	ds.Append(&Diagnostic{
+		Severity: SeverityError,
+		Module:   module,
+		FilePath: filePath,
+		Summary:  err.Error(),
+	})
+}
+
+// AppendHCL converts the hcl.Diagnostics produced while parsing filePath into
+// Diagnostic entries scoped to module, preserving their source ranges and
+// attaching the offending source line when it can be recovered from src.
+func (ds *Diagnostics) AppendHCL(module, filePath string, src []byte, hclDiags hcl.Diagnostics) {
+	for _, d := range hclDiags {
+		severity := SeverityError
+		if d.Severity == hcl.DiagWarning {
+			severity = SeverityWarning
+		}
+
+		diag := &Diagnostic{
+			Severity: severity,
+			Module:   module,
+			FilePath: filePath,
+			Summary:  d.Summary,
+			Detail:   d.Detail,
+			Subject:  d.Subject,
+		}
+		if d.Subject != nil {
+			diag.Snippet = sourceLine(src, d.Subject.Start.Line)
+		}
+
+		ds.Append(diag)
+	}
+}
+
+// HasErrors reports whether the list contains any error-severity diagnostics.
+func (ds Diagnostics) HasErrors() bool {
+	return ds.ErrorCount() > 0
+}
+
+// ErrorCount returns the number of error-severity diagnostics.
+func (ds Diagnostics) ErrorCount() int {
+	count := 0
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			count++
+		}
+	}
+	return count
+}
+
+// WarningCount returns the number of warning-severity diagnostics.
+func (ds Diagnostics) WarningCount() int {
+	count := 0
+	for _, d := range ds {
+		if d.Severity == SeverityWarning {
+			count++
+		}
+	}
+	return count
+}
+
+// sourceLine returns the 1-indexed line from src, or "" if line is out of range.
+func sourceLine(src []byte, line int) string {
+	if line < 1 {
+		return ""
+	}
+
+	lines := strings.Split(string(src), "\n")
+	if line > len(lines) {
+		return ""
+	}
+
+	return lines[line-1]
+}
@@ -0,0 +1,97 @@
+package sync_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lablabs/luactl/internal/sync"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeverity_String(t *testing.T) {
+	assert.Equal(t, "warning", sync.SeverityWarning.String())
+	assert.Equal(t, "error", sync.SeverityError.String())
+	assert.Equal(t, "unknown", sync.Severity(99).String())
+}
+
+func TestDiagnostics_AppendError(t *testing.T) {
+	var diags sync.Diagnostics
+	diags.AppendError("addon", "addon.tf", errors.New("boom"))
+
+	assert.Equal(t, 1, diags.ErrorCount())
+	assert.Zero(t, diags.WarningCount())
+	assert.True(t, diags.HasErrors())
+	assert.Equal(t, "error: addon: addon.tf: boom", diags[0].String())
+}
+
+func TestDiagnostics_AppendHCL(t *testing.T) {
+	src := []byte("variable \"name\" {\n  type = string\n}\n")
+
+	hclDiags := hcl.Diagnostics{
+		{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid type",
+			Detail:   "type must be a valid type expression.",
+			Subject: &hcl.Range{
+				Start: hcl.Pos{Line: 2, Column: 3},
+				End:   hcl.Pos{Line: 2, Column: 16},
+			},
+		},
+		{
+			Severity: hcl.DiagWarning,
+			Summary:  "Deprecated attribute",
+		},
+	}
+
+	var diags sync.Diagnostics
+	diags.AppendHCL("addon", "variables.tf", src, hclDiags)
+
+	assert.Equal(t, 2, len(diags))
+	assert.Equal(t, 1, diags.ErrorCount())
+	assert.Equal(t, 1, diags.WarningCount())
+
+	errDiag := diags[0]
+	assert.Equal(t, sync.SeverityError, errDiag.Severity)
+	assert.Equal(t, "  type = string", errDiag.Snippet)
+	assert.Equal(t, "error: addon: variables.tf:2:3: Invalid type\n  type must be a valid type expression.", errDiag.String())
+
+	warnDiag := diags[1]
+	assert.Equal(t, sync.SeverityWarning, warnDiag.Severity)
+	assert.Nil(t, warnDiag.Subject)
+	assert.Empty(t, warnDiag.Snippet)
+	assert.Equal(t, "warning: addon: variables.tf: Deprecated attribute", warnDiag.String())
+}
+
+func TestDiagnostics_AppendHCL_SubjectPastEndOfFile(t *testing.T) {
+	src := []byte("variable \"name\" {}\n")
+
+	hclDiags := hcl.Diagnostics{
+		{
+			Severity: hcl.DiagError,
+			Summary:  "Unexpected end of file",
+			Subject: &hcl.Range{
+				Start: hcl.Pos{Line: 5, Column: 1},
+				End:   hcl.Pos{Line: 5, Column: 1},
+			},
+		},
+	}
+
+	var diags sync.Diagnostics
+	diags.AppendHCL("addon", "variables.tf", src, hclDiags)
+
+	assert.Len(t, diags, 1)
+	assert.Empty(t, diags[0].Snippet, "a Subject line past the end of src has no snippet to recover")
+}
+
+func TestDiagnostic_String_FilePathWithoutSubject(t *testing.T) {
+	diag := &sync.Diagnostic{
+		Severity: sync.SeverityWarning,
+		Module:   "addon",
+		FilePath: "addon.tf",
+		Summary:  "file is out of sync with the addon module",
+	}
+
+	assert.Equal(t, "warning: addon: addon.tf: file is out of sync with the addon module", diag.String())
+}
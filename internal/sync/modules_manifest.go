@@ -0,0 +1,122 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// modulesManifestFileName is Terraform's module installer manifest, written
+// by "terraform init"/"terraform get" into modulesDir.
+const modulesManifestFileName = "modules.json"
+
+// moduleRecord is a single entry of .terraform/modules/modules.json: Key is
+// the module call address (e.g. "addon_aws_ebs_csi_driver"), Source is where
+// it was installed from, and Dir is its on-disk location relative to the
+// configuration root.
+type moduleRecord struct {
+	Key    string `json:"Key"`
+	Source string `json:"Source"`
+	Dir    string `json:"Dir"`
+}
+
+// modulesManifest is the top-level shape of modules.json.
+type modulesManifest struct {
+	Modules []moduleRecord `json:"Modules"`
+}
+
+// AddonModule locates a single addon module call: Name is its call address
+// (used to derive the generated <addon>.tf/variables-<addon>.tf file names)
+// and SourceDir is the directory that directly contains its variables.tf.
+type AddonModule struct {
+	Name      string
+	SourceDir string
+}
+
+// DiscoverAddonModules enumerates addon module calls to sync. It prefers
+// Terraform's modules.json manifest, matching the addon filter against each
+// record's Key (the module call address) rather than its on-disk directory
+// name, which Terraform hashes for git/registry sources. When modules.json
+// is missing, it falls back to the previous directory-scan heuristic.
+//
+// Exported so callers outside ProcessModules, such as "sync watch"'s
+// filesystem watcher, can discover the same set of addon modules rather than
+// re-implementing the directory-name heuristic this method replaces.
+func (vp *VariableProcessor) DiscoverAddonModules(ctx context.Context) ([]AddonModule, int, error) {
+	manifestPath := filepath.Join(vp.modulesDir, modulesManifestFileName)
+
+	manifestBytes, err := afero.ReadFile(vp.fs, manifestPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			vp.logger.DebugContext(ctx, "modules.json not found, falling back to directory scan", "path", manifestPath)
+			return vp.discoverAddonModulesFromDirectory(ctx)
+		}
+		return nil, 0, fmt.Errorf("failed to read file %q: %w", manifestPath, err)
+	}
+
+	var manifest modulesManifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse %q: %w", manifestPath, err)
+	}
+
+	var modules []AddonModule
+	skippedCount := 0
+	for _, record := range manifest.Modules {
+		if !isAddonModuleName(record.Key) {
+			vp.logger.DebugContext(ctx, "Skipping module", "key", record.Key, "reason", "does not match criteria")
+			skippedCount++
+			continue
+		}
+
+		modules = append(modules, AddonModule{
+			Name:      record.Key,
+			SourceDir: filepath.Join(vp.workDir, record.Dir),
+		})
+	}
+
+	return modules, skippedCount, nil
+}
+
+// discoverAddonModulesFromDirectory is the pre-modules.json heuristic: every
+// directory directly under modulesDir whose name matches the addon filter is
+// treated as an addon module whose call address equals its directory name.
+func (vp *VariableProcessor) discoverAddonModulesFromDirectory(ctx context.Context) ([]AddonModule, int, error) {
+	entries, err := afero.ReadDir(vp.fs, vp.modulesDir)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read directory %q: %w", vp.modulesDir, err)
+	}
+
+	var modules []AddonModule
+	skippedCount := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		if !isAddonModuleName(name) {
+			vp.logger.DebugContext(ctx, "Skipping entry", "module", name, "reason", "does not match criteria")
+			skippedCount++
+			continue
+		}
+
+		modules = append(modules, AddonModule{
+			Name:      name,
+			SourceDir: filepath.Join(vp.modulesDir, name, "modules", name),
+		})
+	}
+
+	return modules, skippedCount, nil
+}
+
+// isAddonModuleName reports whether name matches the addon filter: it starts
+// with addonPrefix and contains no dot.
+func isAddonModuleName(name string) bool {
+	return strings.HasPrefix(name, addonPrefix) && !strings.Contains(name, ".")
+}
@@ -6,17 +6,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
 	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/sync/errgroup"
 )
 
 // Configuration constants.
@@ -26,16 +29,34 @@ const (
 	targetAddonFilePattern    = "%s.tf"
 	targetVariableFilePattern = "variables-%s.tf"
 	targetFileMode            = 0600
+	diffContextLines          = 3
 )
 
 // VariableProcessor handles the parsing, formatting, and writing of variables.
 type VariableProcessor struct {
-	tmpl   *template.Template
-	logger *slog.Logger
+	tmpl        *template.Template
+	logger      *slog.Logger
+	fs          afero.Fs
+	workDir     string
+	targetDir   string
+	modulesDir  string
+	check       bool
+	diff        bool
+	concurrency int
 }
 
-// NewVariableProcessor creates a new processor.
-func NewVariableProcessor(logger *slog.Logger) (*VariableProcessor, error) {
+// NewVariableProcessor creates a new processor rooted at workDir. Source addon
+// modules are read from modulesDir (resolved relative to workDir) and synced
+// files are written to targetDir. When check is true, ProcessModules never
+// mutates targetDir and instead reports drift; when diff is also true, a
+// unified diff is printed for every out-of-date file. concurrency bounds how
+// many addon modules ProcessModules processes in parallel; values less than 1
+// are treated as 1. fsys is the filesystem all reads and writes go through; a
+// nil fsys defaults to afero.NewOsFs(), so existing callers see no behavior
+// change. Passing an afero.NewMemMapFs() instead enables hermetic in-memory
+// tests, and is the seam a future remote-source or --dry-run mode would hook
+// into.
+func NewVariableProcessor(logger *slog.Logger, fsys afero.Fs, workDir, targetDir, modulesDir string, check, diff bool, concurrency int) (*VariableProcessor, error) {
 	tmpl, err := template.New("variables").Parse(
 		`# IMPORTANT: This file is synced with the "terraform-aws-eks-universal-addon" module. Any changes to this file might be overwritten upon the next release of that module.
 {{ printf "%s" .Variables }}`)
@@ -44,108 +65,164 @@ func NewVariableProcessor(logger *slog.Logger) (*VariableProcessor, error) {
 		return nil, fmt.Errorf("failed to create template: %w", err)
 	}
 
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	if fsys == nil {
+		fsys = afero.NewOsFs()
+	}
+
 	return &VariableProcessor{
-		tmpl:   tmpl,
-		logger: logger,
+		tmpl:        tmpl,
+		logger:      logger,
+		fs:          fsys,
+		workDir:     workDir,
+		targetDir:   targetDir,
+		modulesDir:  filepath.Join(workDir, modulesDir),
+		check:       check,
+		diff:        diff,
+		concurrency: concurrency,
 	}, nil
 }
 
-// ProcessModules finds and processes all relevant addon modules in the specified directory.
-func (vp *VariableProcessor) ProcessModules(ctx context.Context, modulesDir string) error {
-	vp.logger.InfoContext(ctx, "Starting variable sync from modules", "modulesDir", modulesDir)
+// ProcessModules finds and processes all relevant addon modules in the
+// configured modules directory, fanning work out across a worker pool bounded
+// by vp.concurrency. Modules are independent of one another, so they're
+// processed concurrently; the first fatal error (or ctx cancellation) stops
+// new workers from starting and propagates out of Wait. It returns the
+// accumulated Diagnostics alongside the usual error so callers can render
+// per-module, per-file context instead of a bare error count.
+func (vp *VariableProcessor) ProcessModules(ctx context.Context) (Diagnostics, error) {
+	var diags Diagnostics
 
-	entries, err := os.ReadDir(modulesDir)
+	vp.logger.InfoContext(ctx, "Starting variable sync from modules", "modulesDir", vp.modulesDir)
+
+	modules, skippedCount, err := vp.DiscoverAddonModules(ctx)
 	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			vp.logger.WarnContext(ctx, "Modules directory not found, skipping sync", "modulesDir", modulesDir)
-			return nil
+		if errors.Is(err, os.ErrNotExist) {
+			vp.logger.WarnContext(ctx, "Modules directory not found, skipping sync", "modulesDir", vp.modulesDir)
+			return diags, nil
 		}
-		vp.logger.ErrorContext(ctx, "Failed to read directory", "path", modulesDir, "error", err)
-		return fmt.Errorf("failed to read directory %q: %w", modulesDir, err)
+		vp.logger.ErrorContext(ctx, "Failed to discover addon modules", "path", vp.modulesDir, "error", err)
+		diags.AppendError("", vp.modulesDir, err)
+		return diags, err
 	}
 
 	var (
+		mu             sync.Mutex
 		processedCount = 0
-		skippedCount   = 0
-		errorCount     = 0
+		driftedCount   = 0
 	)
 
-	for _, entry := range entries {
-		select {
-		case <-ctx.Done():
-			vp.logger.WarnContext(ctx, "Processing cancelled", "error", ctx.Err())
-			return ctx.Err()
-		default:
-		}
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(vp.concurrency)
 
-		if !entry.IsDir() {
-			continue
-		}
+	for _, module := range modules {
+		module := module // capture per iteration: go.mod doesn't pin go >= 1.22
+		group.Go(func() error {
+			select {
+			case <-groupCtx.Done():
+				return groupCtx.Err()
+			default:
+			}
 
-		moduleName := entry.Name()
-		if !strings.HasPrefix(moduleName, addonPrefix) || strings.Contains(moduleName, ".") {
-			vp.logger.DebugContext(ctx, "Skipping entry", "module", moduleName, "reason", "does not match criteria")
-			skippedCount++
-			continue
-		}
+			vp.logger.InfoContext(groupCtx, "Processing module", "module", module.Name)
+
+			var moduleDiags Diagnostics
+			inSync, procErr := vp.processSingleModule(groupCtx, module, &moduleDiags)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			diags = append(diags, moduleDiags...)
+			if procErr != nil {
+				vp.logger.ErrorContext(groupCtx, "Failed to process module", "module", module.Name, "error", procErr)
+				return procErr
+			}
 
-		vp.logger.InfoContext(ctx, "Processing module", "module", moduleName)
-		procErr := vp.processSingleModule(ctx, modulesDir, moduleName)
-		if procErr != nil {
-			errorCount++
-			vp.logger.ErrorContext(ctx, "Failed to process module", "module", moduleName, "error", procErr)
-		} else {
 			processedCount++
-		}
+			if !inSync {
+				driftedCount++
+			}
+			return nil
+		})
 	}
 
+	waitErr := group.Wait()
+
+	errorCount := diags.ErrorCount()
 	vp.logger.InfoContext(ctx, "Variable sync finished",
 		"processed", processedCount,
 		"skipped", skippedCount,
 		"errors", errorCount,
+		"warnings", diags.WarningCount(),
+		"drifted", driftedCount,
 	)
-	if errorCount > 0 {
-		return fmt.Errorf("encountered %d error(s) during processing", errorCount)
+
+	if waitErr != nil {
+		if errors.Is(waitErr, context.Canceled) || errors.Is(waitErr, context.DeadlineExceeded) {
+			return diags, waitErr
+		}
+		return diags, fmt.Errorf("encountered %d error(s) during processing", errorCount)
+	}
+
+	if vp.check && driftedCount > 0 {
+		return diags, fmt.Errorf("%d addon module(s) are out of sync; run sync without --check to update them", driftedCount)
 	}
 
-	return nil
+	return diags, nil
 }
 
-func (vp *VariableProcessor) processSingleModule(ctx context.Context, modulesBaseDir, moduleName string) error {
-	sourcePath := filepath.Join(modulesBaseDir, moduleName, "modules", moduleName, sourceVariableFileName)
+// processSingleModule syncs a single addon module and reports whether the
+// resulting files were already in sync with what is on disk. Failures are
+// recorded on diags as well as returned; processing of the module stops at
+// the first file that fails, so later files of that module are not examined.
+func (vp *VariableProcessor) processSingleModule(ctx context.Context, module AddonModule, diags *Diagnostics) (bool, error) {
+	moduleName := module.Name
+	sourcePath := filepath.Join(module.SourceDir, sourceVariableFileName)
 
 	vp.logger.DebugContext(ctx, "Processing source file", "sourcePath", sourcePath)
 
-	file, err := vp.extractVariables(ctx, sourcePath)
+	file, err := vp.extractVariables(ctx, moduleName, sourcePath, diags)
 	if err != nil {
-		return fmt.Errorf("failed to parse variables: %w", err)
+		return false, fmt.Errorf("failed to parse variables: %w", err)
+	}
+
+	if ctx.Err() != nil {
+		return false, ctx.Err()
 	}
 
-	syncErr := vp.syncAddonDefaults(ctx, moduleName, file)
+	defaultsInSync, syncErr := vp.syncAddonDefaults(ctx, moduleName, file, diags)
 	if syncErr != nil {
-		return fmt.Errorf("failed to sync addon defaults: %w", syncErr)
+		return false, fmt.Errorf("failed to sync addon defaults: %w", syncErr)
 	}
 
-	syncVarErr := vp.syncAddonVariables(ctx, moduleName, file)
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	variablesInSync, syncVarErr := vp.syncAddonVariables(ctx, moduleName, file, diags)
 	if syncVarErr != nil {
-		return fmt.Errorf("failed to sync addon variables: %w", syncVarErr)
+		return false, fmt.Errorf("failed to sync addon variables: %w", syncVarErr)
 	}
 
-	return nil
+	return defaultsInSync && variablesInSync, nil
 }
 
-func (vp *VariableProcessor) extractVariables(ctx context.Context, filePath string) (*hclwrite.File, error) {
-	src, err := os.ReadFile(filePath)
+func (vp *VariableProcessor) extractVariables(ctx context.Context, moduleName, filePath string, diags *Diagnostics) (*hclwrite.File, error) {
+	src, err := afero.ReadFile(vp.fs, filePath)
 	if err != nil {
 		vp.logger.ErrorContext(ctx, "Failed to read file", "path", filePath, "error", err)
+		diags.AppendError(moduleName, filePath, err)
 		return nil, fmt.Errorf("failed to read file %q: %w", filePath, err)
 	}
 
-	file, diags := hclwrite.ParseConfig(src, filepath.Base(filePath), hcl.Pos{Line: 1, Column: 1})
-	if diags.HasErrors() {
-		diagErr := errors.New(diags.Error())
-		vp.logger.ErrorContext(ctx, "Failed to parse HCL", "path", filePath, "error", diagErr)
-		return nil, fmt.Errorf("failed to parse HCL file %q: %w", filePath, diagErr)
+	file, hclDiags := hclwrite.ParseConfig(src, filepath.Base(filePath), hcl.Pos{Line: 1, Column: 1})
+	if hclDiags.HasErrors() {
+		vp.logger.ErrorContext(ctx, "Failed to parse HCL", "path", filePath, "error", hclDiags.Error())
+		diags.AppendHCL(moduleName, filePath, src, hclDiags)
+		return nil, fmt.Errorf("failed to parse HCL file %q: %w", filePath, errors.New(hclDiags.Error()))
 	}
 
 	varFile := hclwrite.NewEmptyFile()
@@ -165,20 +242,37 @@ func (vp *VariableProcessor) extractVariables(ctx context.Context, filePath stri
 	return varFile, nil
 }
 
-func (vp *VariableProcessor) syncAddonDefaults(ctx context.Context, moduleName string, varFile *hclwrite.File) error {
-	filePath := fmt.Sprintf(targetAddonFilePattern, moduleName)
+// syncAddonDefaults renders the updated <addon>.tf file and either writes it
+// or, in check mode, compares it against what is on disk.
+func (vp *VariableProcessor) syncAddonDefaults(ctx context.Context, moduleName string, varFile *hclwrite.File, diags *Diagnostics) (bool, error) {
+	sourcePath := filepath.Join(vp.workDir, fmt.Sprintf(targetAddonFilePattern, moduleName))
 
-	src, err := os.ReadFile(filePath)
+	src, err := afero.ReadFile(vp.fs, sourcePath)
 	if err != nil {
-		vp.logger.ErrorContext(ctx, "Failed to read file", "path", filePath, "error", err)
-		return fmt.Errorf("failed to read file %q: %w", filePath, err)
+		vp.logger.ErrorContext(ctx, "Failed to read file", "path", sourcePath, "error", err)
+		diags.AppendError(moduleName, sourcePath, err)
+		return false, fmt.Errorf("failed to read file %q: %w", sourcePath, err)
 	}
 
-	file, diags := hclwrite.ParseConfig(src, filepath.Base(filePath), hcl.Pos{Line: 1, Column: 1})
-	if diags.HasErrors() {
-		diagErr := errors.New(diags.Error())
-		vp.logger.ErrorContext(ctx, "Failed to parse HCL", "path", filePath, "error", diagErr)
-		return fmt.Errorf("failed to parse HCL file %q: %w", filePath, diagErr)
+	rendered, renderErr := vp.renderAddonDefaults(ctx, moduleName, sourcePath, src, varFile, diags)
+	if renderErr != nil {
+		return false, renderErr
+	}
+
+	targetPath := filepath.Join(vp.targetDir, fmt.Sprintf(targetAddonFilePattern, moduleName))
+	return vp.writeOrCheck(ctx, moduleName, targetPath, rendered, diags)
+}
+
+// renderAddonDefaults rewrites `try`/`lookup` module attributes to use
+// var.<name> when it is non-null and otherwise look the value up in the
+// addon's local.addon map, returning the rendered file bytes without writing
+// anything to disk.
+func (vp *VariableProcessor) renderAddonDefaults(ctx context.Context, moduleName, sourcePath string, src []byte, varFile *hclwrite.File, diags *Diagnostics) ([]byte, error) {
+	file, hclDiags := hclwrite.ParseConfig(src, filepath.Base(sourcePath), hcl.Pos{Line: 1, Column: 1})
+	if hclDiags.HasErrors() {
+		vp.logger.ErrorContext(ctx, "Failed to parse HCL", "path", sourcePath, "error", hclDiags.Error())
+		diags.AppendHCL(moduleName, sourcePath, src, hclDiags)
+		return nil, fmt.Errorf("failed to parse HCL file %q: %w", sourcePath, errors.New(hclDiags.Error()))
 	}
 
 	defaults := make(map[string]hclwrite.Tokens)
@@ -252,19 +346,27 @@ func (vp *VariableProcessor) syncAddonDefaults(ctx context.Context, moduleName s
 		}
 	}
 
-	writeErr := os.WriteFile(filePath, file.Bytes(), targetFileMode)
-	if writeErr != nil {
-		vp.logger.ErrorContext(ctx, "Failed to write file", "path", filePath, "error", writeErr)
-		return fmt.Errorf("failed to write file %q: %w", filePath, writeErr)
+	return file.Bytes(), nil
+}
+
+// syncAddonVariables renders the updated variables-<addon>.tf file and either
+// writes it or, in check mode, compares it against what is on disk.
+func (vp *VariableProcessor) syncAddonVariables(ctx context.Context, moduleName string, varFile *hclwrite.File, diags *Diagnostics) (bool, error) {
+	targetPath := filepath.Join(vp.targetDir, fmt.Sprintf(targetVariableFilePattern, moduleName))
+
+	rendered, renderErr := vp.renderAddonVariables(ctx, varFile)
+	if renderErr != nil {
+		diags.AppendError(moduleName, targetPath, renderErr)
+		return false, renderErr
 	}
 
-	vp.logger.InfoContext(ctx, "Successfully wrote addon defaults", "targetPath", filePath)
-	return nil
+	return vp.writeOrCheck(ctx, moduleName, targetPath, rendered, diags)
 }
 
-func (vp *VariableProcessor) syncAddonVariables(ctx context.Context, moduleName string, varFile *hclwrite.File) error {
-	filePath := fmt.Sprintf(targetVariableFilePattern, moduleName)
-
+// renderAddonVariables strips the "enabled" variable, nulls out defaults, and
+// wraps the result with the generated-file banner, returning the rendered
+// bytes without writing anything to disk.
+func (vp *VariableProcessor) renderAddonVariables(ctx context.Context, varFile *hclwrite.File) ([]byte, error) {
 	file := hclwrite.NewEmptyFile()
 
 	for _, block := range varFile.Body().Blocks() {
@@ -308,16 +410,64 @@ func (vp *VariableProcessor) syncAddonVariables(ctx context.Context, moduleName
 		Variables: file.Bytes(),
 	})
 	if execErr != nil {
-		vp.logger.ErrorContext(ctx, "Failed to execute template", "path", filePath, "error", execErr)
-		return fmt.Errorf("failed to execute template for file %q: %w", filePath, execErr)
+		vp.logger.ErrorContext(ctx, "Failed to execute template", "error", execErr)
+		return nil, fmt.Errorf("failed to execute template: %w", execErr)
 	}
 
-	writeErr := os.WriteFile(filePath, buf.Bytes(), targetFileMode)
-	if writeErr != nil {
-		vp.logger.ErrorContext(ctx, "Failed to write file", "path", filePath, "error", writeErr)
-		return fmt.Errorf("failed to write file %q: %w", filePath, writeErr)
+	return buf.Bytes(), nil
+}
+
+// writeOrCheck persists rendered to filePath, unless the processor is running
+// in check mode, in which case it compares rendered against the file already
+// on disk and, when diff is enabled, prints a unified diff of the drift. It
+// reports whether filePath already matched rendered.
+func (vp *VariableProcessor) writeOrCheck(ctx context.Context, moduleName, filePath string, rendered []byte, diags *Diagnostics) (bool, error) {
+	if !vp.check {
+		if err := afero.WriteFile(vp.fs, filePath, rendered, targetFileMode); err != nil {
+			vp.logger.ErrorContext(ctx, "Failed to write file", "path", filePath, "error", err)
+			diags.AppendError(moduleName, filePath, err)
+			return false, fmt.Errorf("failed to write file %q: %w", filePath, err)
+		}
+
+		vp.logger.InfoContext(ctx, "Successfully wrote file", "targetPath", filePath)
+		return true, nil
+	}
+
+	existing, err := afero.ReadFile(vp.fs, filePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			vp.logger.ErrorContext(ctx, "Failed to read file", "path", filePath, "error", err)
+			diags.AppendError(moduleName, filePath, err)
+			return false, fmt.Errorf("failed to read file %q: %w", filePath, err)
+		}
+		existing = nil
+	}
+
+	if bytes.Equal(existing, rendered) {
+		return true, nil
+	}
+
+	diags.Append(&Diagnostic{
+		Severity: SeverityWarning,
+		Module:   moduleName,
+		FilePath: filePath,
+		Summary:  "file is out of sync with the addon module",
+	})
+
+	if vp.diff {
+		text, diffErr := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(existing)),
+			B:        difflib.SplitLines(string(rendered)),
+			FromFile: filePath,
+			ToFile:   filePath,
+			Context:  diffContextLines,
+		})
+		if diffErr != nil {
+			vp.logger.ErrorContext(ctx, "Failed to compute diff", "path", filePath, "error", diffErr)
+		} else {
+			fmt.Fprint(os.Stdout, text)
+		}
 	}
 
-	vp.logger.InfoContext(ctx, "Successfully wrote addon variables", "targetPath", filePath)
-	return nil
+	return false, nil
 }
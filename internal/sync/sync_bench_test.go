@@ -0,0 +1,76 @@
+package sync_test
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/lablabs/luactl/internal/sync"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const benchmarkModuleCount = 50
+
+// BenchmarkProcessModules measures how ProcessModules scales as its worker
+// pool grows, on a fixture of synthetic addon modules.
+func BenchmarkProcessModules(b *testing.B) {
+	workDir := generateSyntheticAddons(b, benchmarkModuleCount)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, concurrency := range []int{1, 2, 4, runtime.NumCPU()} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				processor, err := sync.NewVariableProcessor(logger, afero.NewOsFs(), workDir, b.TempDir(), "modules", false, false, concurrency)
+				require.NoError(b, err)
+
+				_, err = processor.ProcessModules(b.Context())
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+// generateSyntheticAddons writes count independent addon modules under a
+// temporary work directory, mirroring the layout ProcessModules expects:
+// <workDir>/modules/addonN/modules/addonN/variables.tf and <workDir>/addonN.tf.
+func generateSyntheticAddons(b *testing.B, count int) string {
+	b.Helper()
+
+	workDir := b.TempDir()
+
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("addon%d", i)
+
+		moduleDir := filepath.Join(workDir, "modules", name, "modules", name)
+		require.NoError(b, os.MkdirAll(moduleDir, 0755))
+
+		variablesTf := `variable "enabled" {
+  type    = bool
+  default = true
+}
+
+variable "name" {
+  description = "Name override."
+  type        = string
+  default     = ""
+}
+`
+		require.NoError(b, os.WriteFile(filepath.Join(moduleDir, "variables.tf"), []byte(variablesTf), 0600))
+
+		addonTf := fmt.Sprintf(`module %q {
+  source = "./modules/%s"
+
+  name = try(var.name, null)
+}
+`, name, name)
+		require.NoError(b, os.WriteFile(filepath.Join(workDir, name+".tf"), []byte(addonTf), 0600))
+	}
+
+	return workDir
+}
@@ -0,0 +1,145 @@
+package sync_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lablabs/luactl/internal/sync"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCheckFixture populates workDir in fs with a variables.tf/addon.tf pair
+// that ProcessModules can sync, mirroring the MemMapFs fixtures used for the
+// write-mode tests.
+func writeCheckFixture(t *testing.T, fs afero.Fs, workDir string) {
+	t.Helper()
+
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(workDir, "modules/addon/modules/addon/variables.tf"), []byte(`variable "enabled" {
+  type    = bool
+  default = true
+}
+
+variable "name" {
+  description = "Name override."
+  type        = string
+  default     = ""
+}
+`), 0600))
+
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(workDir, "addon.tf"), []byte(`module "addon" {
+  source = "./modules/addon"
+
+  name = try(var.name, null)
+}
+`), 0600))
+}
+
+func newCheckLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+}
+
+func TestProcessModules_CheckMode_NoDrift(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeCheckFixture(t, fs, "/work")
+	logger := newCheckLogger()
+
+	writer, err := sync.NewVariableProcessor(logger, fs, "/work", "/target", "modules", false, false, 1)
+	require.NoError(t, err)
+	_, err = writer.ProcessModules(t.Context())
+	require.NoError(t, err)
+
+	checker, err := sync.NewVariableProcessor(logger, fs, "/work", "/target", "modules", true, false, 1)
+	require.NoError(t, err)
+	diags, err := checker.ProcessModules(t.Context())
+	require.NoError(t, err)
+	assert.Zero(t, diags.WarningCount())
+}
+
+func TestProcessModules_CheckMode_Drift(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeCheckFixture(t, fs, "/work")
+	logger := newCheckLogger()
+
+	writer, err := sync.NewVariableProcessor(logger, fs, "/work", "/target", "modules", false, false, 1)
+	require.NoError(t, err)
+	_, err = writer.ProcessModules(t.Context())
+	require.NoError(t, err)
+
+	// Simulate drift by hand-editing the generated file after it was written.
+	require.NoError(t, afero.WriteFile(fs, "/target/addon.tf", []byte(`module "addon" {
+  source = "./modules/addon"
+}
+`), 0600))
+
+	checker, err := sync.NewVariableProcessor(logger, fs, "/work", "/target", "modules", true, false, 1)
+	require.NoError(t, err)
+	diags, err := checker.ProcessModules(t.Context())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of sync")
+	assert.Equal(t, 1, diags.WarningCount())
+
+	// Check mode must never touch the target files.
+	addonTf, readErr := afero.ReadFile(fs, "/target/addon.tf")
+	require.NoError(t, readErr)
+	assert.NotContains(t, string(addonTf), "lookup")
+}
+
+func TestProcessModules_CheckMode_MissingFileIsDrift(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeCheckFixture(t, fs, "/work")
+	logger := newCheckLogger()
+
+	checker, err := sync.NewVariableProcessor(logger, fs, "/work", "/target", "modules", true, false, 1)
+	require.NoError(t, err)
+	_, err = checker.ProcessModules(t.Context())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of sync")
+
+	_, statErr := fs.Stat("/target/addon.tf")
+	assert.True(t, errors.Is(statErr, os.ErrNotExist), "check mode must not create the missing file")
+}
+
+func TestProcessModules_DiffMode_PrintsUnifiedDiff(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeCheckFixture(t, fs, "/work")
+	logger := newCheckLogger()
+
+	writer, err := sync.NewVariableProcessor(logger, fs, "/work", "/target", "modules", false, false, 1)
+	require.NoError(t, err)
+	_, err = writer.ProcessModules(t.Context())
+	require.NoError(t, err)
+
+	require.NoError(t, afero.WriteFile(fs, "/target/addon.tf", []byte(`module "addon" {
+  source = "./modules/addon"
+
+  name = null
+}
+`), 0600))
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+
+	checker, procErr := sync.NewVariableProcessor(logger, fs, "/work", "/target", "modules", true, true, 1)
+	require.NoError(t, procErr)
+	_, syncErr := checker.ProcessModules(t.Context())
+
+	require.NoError(t, w.Close())
+	os.Stdout = stdout
+	out, readErr := io.ReadAll(r)
+	require.NoError(t, readErr)
+
+	require.Error(t, syncErr)
+	assert.Contains(t, string(out), `-  name = null`)
+	assert.Contains(t, string(out), `+  name = var.name != null`)
+}
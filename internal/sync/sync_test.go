@@ -1,44 +1,158 @@
 package sync_test
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
 	"testing"
 
 	"github.com/lablabs/luactl/internal/sync"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestProcessModules(t *testing.T) {
-	// Create the module directory with the required structure
-	targetDir := filepath.Join(t.TempDir(), "tests", "fixture")
-	require.NoError(t, os.MkdirAll(targetDir, 0755))
+// TestProcessModules_MemMapFs exercises the same sync logic entirely against
+// an in-memory filesystem, with no fixtures on disk and no t.TempDir().
+func TestProcessModules_MemMapFs(t *testing.T) {
+	fs := afero.NewMemMapFs()
 
-	// Read the expected output files
-	expectedVariablesContent, err := os.ReadFile(filepath.Join("tests", "fixture", "variables-addon.tf"))
+	require.NoError(t, afero.WriteFile(fs, "/work/modules/addon/modules/addon/variables.tf", []byte(`variable "enabled" {
+  type    = bool
+  default = true
+}
+
+variable "name" {
+  description = "Name override."
+  type        = string
+  default     = ""
+}
+`), 0600))
+
+	require.NoError(t, afero.WriteFile(fs, "/work/addon.tf", []byte(`module "addon" {
+  source = "./modules/addon"
+
+  name = try(var.name, null)
+}
+`), 0600))
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	processor, err := sync.NewVariableProcessor(logger, fs, "/work", "/work", "modules", false, false, 1)
+	require.NoError(t, err)
+
+	_, err = processor.ProcessModules(t.Context())
+	require.NoError(t, err)
+
+	addonTf, err := afero.ReadFile(fs, "/work/addon.tf")
 	require.NoError(t, err)
+	assert.Contains(t, string(addonTf), `lookup(local.addon, "name", "")`)
+
+	variablesTf, err := afero.ReadFile(fs, "/work/variables-addon.tf")
+	require.NoError(t, err)
+	assert.Contains(t, string(variablesTf), `variable "name"`)
+	assert.NotContains(t, string(variablesTf), `variable "enabled"`)
+}
+
+// TestProcessModules_ModulesManifest exercises discovery via modules.json,
+// where the on-disk directory is a content hash that bears no resemblance to
+// the module's call address, so matching must key off the manifest record
+// rather than the directory name.
+func TestProcessModules_ModulesManifest(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, "/work/modules/modules.json", []byte(`{
+  "Modules": [
+    {"Key": "addon", "Source": "git::https://example.com/addon.git", "Dir": "modules/3f9c1a2/modules/addon"},
+    {"Key": "not_an_addon", "Source": "./not-an-addon", "Dir": "modules/not-an-addon"}
+  ]
+}
+`), 0600))
+
+	require.NoError(t, afero.WriteFile(fs, "/work/modules/3f9c1a2/modules/addon/variables.tf", []byte(`variable "enabled" {
+  type    = bool
+  default = true
+}
+
+variable "name" {
+  description = "Name override."
+  type        = string
+  default     = ""
+}
+`), 0600))
+
+	require.NoError(t, afero.WriteFile(fs, "/work/addon.tf", []byte(`module "addon" {
+  source = "git::https://example.com/addon.git"
+
+  name = try(var.name, null)
+}
+`), 0600))
 
-	// Configure a logger for testing
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 		Level: slog.LevelDebug,
 	}))
 
-	// Create a test processor
-	processor, err := sync.NewVariableProcessor(logger, "tests/fixture", targetDir, "modules")
+	processor, err := sync.NewVariableProcessor(logger, fs, "/work", "/work", "modules", false, false, 1)
+	require.NoError(t, err)
+
+	_, err = processor.ProcessModules(t.Context())
 	require.NoError(t, err)
 
-	// Process the modules
-	err = processor.ProcessModules(t.Context())
+	addonTf, err := afero.ReadFile(fs, "/work/addon.tf")
+	require.NoError(t, err)
+	assert.Contains(t, string(addonTf), `lookup(local.addon, "name", "")`)
+
+	variablesTf, err := afero.ReadFile(fs, "/work/variables-addon.tf")
+	require.NoError(t, err)
+	assert.Contains(t, string(variablesTf), `variable "name"`)
+}
+
+// TestProcessModules_ConcurrentModulesEachGetOwnContent guards against the
+// worker goroutines racing on a shared loop variable: with several distinct
+// addon modules and concurrency > 1, every module's generated file must
+// contain that module's own description, never a sibling's.
+func TestProcessModules_ConcurrentModulesEachGetOwnContent(t *testing.T) {
+	const moduleCount = 20
+
+	fs := afero.NewMemMapFs()
+
+	for i := 0; i < moduleCount; i++ {
+		name := fmt.Sprintf("addon%d", i)
+
+		require.NoError(t, afero.WriteFile(fs, fmt.Sprintf("/work/modules/%s/modules/%s/variables.tf", name, name), []byte(fmt.Sprintf(`variable "name" {
+  description = "Name override for %s."
+  type        = string
+  default     = ""
+}
+`, name)), 0600))
+
+		require.NoError(t, afero.WriteFile(fs, fmt.Sprintf("/work/%s.tf", name), []byte(fmt.Sprintf(`module "%s" {
+  source = "./modules/%s"
+
+  name = try(var.name, null)
+}
+`, name, name)), 0600))
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	processor, err := sync.NewVariableProcessor(logger, fs, "/work", "/work", "modules", false, false, 8)
 	require.NoError(t, err)
 
-	actualVariablesPath := filepath.Join(targetDir, "variables-addon.tf")
-	actualVariables, err := os.ReadFile(actualVariablesPath)
+	_, err = processor.ProcessModules(t.Context())
 	require.NoError(t, err)
 
-	// Compare the generated files with expected fixtures
-	assert.Equal(t, string(expectedVariablesContent), string(actualVariables),
-		"Generated variables-addon.tf doesn't match expected content")
+	for i := 0; i < moduleCount; i++ {
+		name := fmt.Sprintf("addon%d", i)
+
+		variablesTf, readErr := afero.ReadFile(fs, fmt.Sprintf("/work/variables-%s.tf", name))
+		require.NoError(t, readErr, "module %s must have its own generated file", name)
+		assert.Contains(t, string(variablesTf), fmt.Sprintf("Name override for %s.", name),
+			"module %s's generated file must contain its own content, not a sibling's", name)
+	}
 }